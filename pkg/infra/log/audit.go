@@ -0,0 +1,106 @@
+package log
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/log/level"
+)
+
+// AuditContext carries the actor performing a mutation so it can be attached
+// to audit records without threading extra parameters through every layer.
+// Middleware populates it on the request context; code that emits an audit
+// record downstream of the request (e.g. sqlstore) just reads it back out.
+type AuditContext struct {
+	UserID int64
+	IP     string
+}
+
+// auditSystemActor is used whenever a mutation happens with no request in
+// flight to carry an AuditContext (migrations, background jobs, tests).
+const auditSystemActor = "system"
+
+type auditContextKey struct{}
+
+// WithAuditContext returns a copy of ctx carrying ac, retrievable later with
+// AuditActor.
+func WithAuditContext(ctx context.Context, ac AuditContext) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, ac)
+}
+
+// AuditActor returns the actor user id and IP to attach to an audit record,
+// falling back to "system"/"-" when ctx carries no AuditContext.
+func AuditActor(ctx context.Context) (actorUserID string, actorIP string) {
+	ac, ok := ctx.Value(auditContextKey{}).(AuditContext)
+	if !ok {
+		return auditSystemActor, "-"
+	}
+	return fmt.Sprintf("%d", ac.UserID), ac.IP
+}
+
+// AuditRecord is a single tamper-evident audit entry. Only non-secret,
+// already-public-ish fields belong here: never put JsonData, SecureJsonData
+// or credentials in a record, since it serializes into the hash chain and
+// the configured sink verbatim.
+type AuditRecord struct {
+	Action        string
+	DSUID         string
+	DSType        string
+	OrgID         int64
+	VersionBefore int
+	VersionAfter  int
+}
+
+var (
+	auditChainMu  sync.Mutex
+	auditPrevHash string
+
+	// auditUnconfiguredWarned makes the "no audit sink configured" warning
+	// fire once instead of once per record, since Audit may be called on
+	// every mutating request.
+	auditUnconfiguredWarned bool
+)
+
+// Audit appends rec to the tamper-evident audit chain and emits it through
+// the dedicated "audit" logger configured by ReadAuditConfig. Each record's
+// hash covers the previous record's hash plus its own fields, so removing or
+// reordering a record downstream breaks the chain for every record after it.
+// If ReadAuditConfig was never called, auditLogger has no loggers registered
+// and would otherwise drop every record without a trace; Audit logs a
+// one-time warning through the root logger in that case instead.
+func Audit(ctx context.Context, rec AuditRecord) {
+	actorUserID, actorIP := AuditActor(ctx)
+
+	auditChainMu.Lock()
+	defer auditChainMu.Unlock()
+
+	if len(auditLogger.loggers) == 0 && !auditUnconfiguredWarned {
+		auditUnconfiguredWarned = true
+		Warn("Audit record dropped: no [audit] sink configured, call ReadAuditConfig at startup", "action", rec.Action)
+	}
+
+	prevHash := auditPrevHash
+	serialized := fmt.Sprintf(
+		"action=%s ds_uid=%s ds_type=%s org_id=%d actor_user_id=%s actor_ip=%s version_before=%d version_after=%d prev_hash=%s",
+		rec.Action, rec.DSUID, rec.DSType, rec.OrgID, actorUserID, actorIP, rec.VersionBefore, rec.VersionAfter, prevHash,
+	)
+	sum := sha256.Sum256([]byte(serialized))
+	hash := hex.EncodeToString(sum[:])
+	auditPrevHash = hash
+
+	auditLogger.LogWithLevel(level.Info,
+		"action", rec.Action,
+		"ds_uid", rec.DSUID,
+		"ds_type", rec.DSType,
+		"org_id", rec.OrgID,
+		"actor_user_id", actorUserID,
+		"actor_ip", actorIP,
+		"version_before", rec.VersionBefore,
+		"version_after", rec.VersionAfter,
+		"prev_hash", prevHash,
+		"hash", hash,
+	)
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAuditActorFallsBackToSystem(t *testing.T) {
+	userID, ip := AuditActor(context.Background())
+	if userID != auditSystemActor || ip != "-" {
+		t.Fatalf("AuditActor() = (%q, %q), want (%q, \"-\")", userID, ip, auditSystemActor)
+	}
+}
+
+// TestAuditHashChain exercises the chaining property directly through
+// auditPrevHash rather than through the configured sink, since Audit's only
+// externally observable effect besides the emitted log line is the chain
+// state it carries forward to the next record.
+func TestAuditHashChain(t *testing.T) {
+	savedPrevHash := auditPrevHash
+	defer func() { auditPrevHash = savedPrevHash }()
+
+	ctx := WithAuditContext(context.Background(), AuditContext{UserID: 7, IP: "10.0.0.1"})
+
+	auditPrevHash = ""
+	Audit(ctx, AuditRecord{Action: "update", DSUID: "abc", DSType: "prometheus", OrgID: 1, VersionBefore: 1, VersionAfter: 2})
+	firstHash := auditPrevHash
+	if firstHash == "" {
+		t.Fatal("expected the first Audit call to set a non-empty chain hash")
+	}
+
+	Audit(ctx, AuditRecord{Action: "update", DSUID: "abc", DSType: "prometheus", OrgID: 1, VersionBefore: 2, VersionAfter: 3})
+	secondHash := auditPrevHash
+	if secondHash == firstHash {
+		t.Fatal("expected the second record's hash to differ from the first")
+	}
+
+	// Replaying the exact same second record against a chain reset to ""
+	// must not reproduce secondHash: the hash covers prev_hash, so removing
+	// or reordering a record changes every hash after it in the chain.
+	auditPrevHash = ""
+	Audit(ctx, AuditRecord{Action: "update", DSUID: "abc", DSType: "prometheus", OrgID: 1, VersionBefore: 2, VersionAfter: 3})
+	if auditPrevHash == secondHash {
+		t.Fatal("expected the hash to depend on prev_hash, not just the record fields")
+	}
+}
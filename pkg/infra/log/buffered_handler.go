@@ -0,0 +1,228 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	bufferDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grafana_log_buffer_dropped_total",
+		Help: "Number of log records dropped by a buffered log handler because its queue was full.",
+	}, []string{"mode"})
+
+	bufferQueueLen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "grafana_log_buffer_queue_len",
+		Help: "Current number of log records waiting in a buffered log handler's queue.",
+	}, []string{"mode"})
+)
+
+func init() {
+	prometheus.MustRegister(bufferDroppedTotal, bufferQueueLen)
+}
+
+// OverflowStrategy controls what a BufferedHandler does when its queue is full.
+type OverflowStrategy string
+
+const (
+	OverflowBlock      OverflowStrategy = "block"
+	OverflowDropOldest OverflowStrategy = "drop_oldest"
+	OverflowDropNew    OverflowStrategy = "drop_new"
+)
+
+// BufferedHandler wraps a log.Logger with a bounded, buffered queue so that a
+// slow sink (disk, syslog) can't stall the goroutine producing log records.
+// A single goroutine dequeues records and forwards them to the wrapped
+// logger in order. A forward that hasn't finished within closeDrain is left
+// running in the background while the next record is forwarded, so after
+// such a timeout records can reach the wrapped logger out of order.
+type BufferedHandler struct {
+	mode     string
+	wrapped  log.Logger
+	overflow OverflowStrategy
+
+	queue      chan []interface{}
+	closeDrain time.Duration
+	done       chan struct{}
+	stopped    chan struct{}
+	mu         sync.Mutex // guards queue sends racing with Close
+	closed     bool
+}
+
+// NewBufferedHandler starts the dequeue goroutine and returns a handler ready
+// to accept log records. closeDrainTimeout bounds how long Close() waits for
+// the queue to drain before giving up.
+func NewBufferedHandler(mode string, wrapped log.Logger, bufferLen int, overflow OverflowStrategy, closeDrainTimeout time.Duration) *BufferedHandler {
+	if bufferLen <= 0 {
+		bufferLen = 10000
+	}
+
+	h := &BufferedHandler{
+		mode:       mode,
+		wrapped:    wrapped,
+		overflow:   overflow,
+		queue:      make(chan []interface{}, bufferLen),
+		closeDrain: closeDrainTimeout,
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	go h.run()
+	return h
+}
+
+func (h *BufferedHandler) run() {
+	defer close(h.stopped)
+	for {
+		// Check h.done first, non-blocking: once Close() has fired, prefer
+		// draining over picking up more work from the queue. Without this,
+		// a hot queue and a closed h.done are both always-ready select
+		// cases, and select breaks the tie uniformly at random instead of
+		// favoring shutdown.
+		select {
+		case <-h.done:
+			h.drain()
+			return
+		default:
+		}
+
+		select {
+		case keyvals, ok := <-h.queue:
+			if !ok {
+				return
+			}
+			// Bounded by closeDrain even outside of Close(): if keyvals
+			// wins the race below right as Close() fires, this is the one
+			// forward call that can still run after h.done closes, so it
+			// must be bounded the same way drain()'s forwards are.
+			h.forwardWithDeadline(keyvals, h.closeDrain)
+		case <-h.done:
+			h.drain()
+			return
+		}
+	}
+}
+
+// drain forwards whatever is left in the queue, bounded by closeDrain as a
+// wall-clock deadline rather than a select-case race: with `case
+// <-h.queue` and `case <-deadline` both ready, select picks between them
+// uniformly at random, so a slow forward() could keep winning over the
+// expired deadline. Checking time.Now() against an explicit deadline (and
+// bounding each individual forward() call so one stalled write can't hang
+// the whole drain) makes it an actual upper bound.
+func (h *BufferedHandler) drain() {
+	deadline := time.Now().Add(h.closeDrain)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return
+		}
+
+		select {
+		case keyvals, ok := <-h.queue:
+			if !ok {
+				return
+			}
+			if !h.forwardWithDeadline(keyvals, remaining) {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// forwardWithDeadline runs forward(keyvals) but gives up waiting for it
+// after timeout, so a wrapped handler stuck on a slow disk/syslog write
+// can't hold Close() open past closeDrain. The forward call itself is left
+// running in the background; it's expected to finish or be abandoned at
+// process exit.
+func (h *BufferedHandler) forwardWithDeadline(keyvals []interface{}, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		h.forward(keyvals)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (h *BufferedHandler) forward(keyvals []interface{}) {
+	bufferQueueLen.WithLabelValues(h.mode).Set(float64(len(h.queue)))
+	if err := h.wrapped.Log(keyvals...); err != nil {
+		Error("Buffered log handler failed to forward record", "mode", h.mode, "err", err)
+	}
+}
+
+func (h *BufferedHandler) Log(keyvals ...interface{}) error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return h.wrapped.Log(keyvals...)
+	}
+	h.mu.Unlock()
+
+	switch h.overflow {
+	case OverflowDropNew:
+		select {
+		case h.queue <- keyvals:
+		default:
+			bufferDroppedTotal.WithLabelValues(h.mode).Inc()
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case h.queue <- keyvals:
+				bufferQueueLen.WithLabelValues(h.mode).Set(float64(len(h.queue)))
+				return nil
+			default:
+				select {
+				case <-h.queue:
+					bufferDroppedTotal.WithLabelValues(h.mode).Inc()
+				default:
+				}
+			}
+		}
+	default: // OverflowBlock
+		// Plain "h.queue <- keyvals" would race Close(): once run() has
+		// seen h.done and returned, nothing drains the queue anymore, so a
+		// blocking send against a full queue would hang forever. Selecting
+		// on h.done alongside the send means a concurrent Close() always
+		// unblocks us, falling back to the same synchronous path the
+		// h.closed check above takes.
+		select {
+		case h.queue <- keyvals:
+		case <-h.done:
+			return h.wrapped.Log(keyvals...)
+		}
+	}
+
+	bufferQueueLen.WithLabelValues(h.mode).Set(float64(len(h.queue)))
+	return nil
+}
+
+// Close stops queueing new records (Log then writes them straight to the
+// wrapped logger), waits up to closeDrainTimeout for the queue to drain,
+// then returns. A forward already in flight when Close is called can add up
+// to one more closeDrainTimeout. It does not close the wrapped logger.
+func (h *BufferedHandler) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.mu.Unlock()
+
+	close(h.done)
+	<-h.stopped
+	return nil
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger is a go-kit log.Logger that records every call it
+// receives, optionally blocking until block is closed first so tests can
+// simulate a sink stuck on a slow disk/syslog write.
+type recordingLogger struct {
+	mu      sync.Mutex
+	records [][]interface{}
+	block   chan struct{}
+}
+
+func (l *recordingLogger) Log(keyvals ...interface{}) error {
+	if l.block != nil {
+		<-l.block
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, keyvals)
+	return nil
+}
+
+func (l *recordingLogger) recordCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.records)
+}
+
+func TestBufferedHandlerOverflowDropNewDoesNotBlock(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	wrapped := &recordingLogger{block: block}
+
+	h := NewBufferedHandler("test", wrapped, 1, OverflowDropNew, 50*time.Millisecond)
+	defer func() { _ = h.Close() }()
+
+	// The first record is immediately dequeued by run() and blocks inside
+	// wrapped.Log, leaving the capacity-1 queue empty and ready for exactly
+	// one more record. Every record after that must be dropped, not block.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			if err := h.Log("i", i); err != nil {
+				t.Errorf("Log: %v", err)
+			}
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Log() blocked under OverflowDropNew instead of dropping the record")
+	}
+}
+
+// TestBufferedHandlerCloseIsBoundedByStuckWrite is a regression test for the
+// fix that routes every forward() in run() through forwardWithDeadline: a
+// wrapped logger that blocks forever used to make Close() hang, because
+// run() could be stuck inside an unbounded forward() call and never reach
+// drain() (or select's random tie-break between h.queue and h.done could
+// keep favoring the queue over shutdown).
+func TestBufferedHandlerCloseIsBoundedByStuckWrite(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	wrapped := &recordingLogger{block: block}
+
+	h := NewBufferedHandler("test", wrapped, 10, OverflowBlock, 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if err := h.Log("i", i); err != nil {
+			t.Fatalf("Log: %v", err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- h.Close() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return within the bounded drain timeout")
+	}
+}
+
+// TestBufferedHandlerOverflowBlockUnblocksOnClose is a regression test for
+// the race between Log()'s OverflowBlock send and a concurrent Close(): once
+// run() has returned, nothing reads from a full queue anymore, so a plain
+// "h.queue <- keyvals" would hang forever instead of respecting Close().
+func TestBufferedHandlerOverflowBlockUnblocksOnClose(t *testing.T) {
+	block := make(chan struct{})
+	wrapped := &recordingLogger{block: block}
+
+	h := NewBufferedHandler("test", wrapped, 1, OverflowBlock, 50*time.Millisecond)
+
+	// Fill the queue: one record is immediately dequeued by run() and
+	// blocks inside wrapped.Log, the other fills the capacity-1 queue.
+	if err := h.Log("i", 0); err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+	if err := h.Log("i", 1); err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+
+	blockedLog := make(chan error, 1)
+	go func() { blockedLog <- h.Log("i", 2) }()
+
+	closeDone := make(chan error, 1)
+	go func() { closeDone <- h.Close() }()
+
+	select {
+	case err := <-closeDone:
+		if err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return; a pending OverflowBlock send is holding it open")
+	}
+
+	close(block)
+
+	select {
+	case err := <-blockedLog:
+		if err != nil {
+			t.Fatalf("Log: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Log() under OverflowBlock never unblocked after Close()")
+	}
+}
@@ -0,0 +1,485 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+// DisposableHandler is implemented by handlers that own resources (open files,
+// sockets, background goroutines) which must be released when the logging
+// pipeline is torn down or reconfigured.
+type DisposableHandler interface {
+	Close() error
+}
+
+// ReloadableHandler is implemented by handlers that need to react to a config
+// reload, e.g. to re-open a file after logrotate(8) has renamed it away from
+// under us.
+type ReloadableHandler interface {
+	Reload() error
+}
+
+const compressTmpSuffix = ".tmp"
+
+// FileWriter is a log.Logger that writes records to a file on disk, rotating
+// it by line count, size, or day, and optionally gzip-compressing and pruning
+// old rotations.
+type FileWriter struct {
+	Filename string
+	Format   Formatedlogger
+
+	Rotate   bool
+	Daily    bool
+	Maxlines int
+	Maxsize  int
+	// Maxdays is a day-based retention policy: rotations (compressed or
+	// not) older than this many days are deleted. Zero disables the policy.
+	Maxdays int64
+
+	// Compress gzips rotated files on a background goroutine instead of
+	// leaving them as plain text.
+	Compress bool
+	// CompressAfter delays compression until a rotation is at least this
+	// many rotations old, so the most recent rotation stays readable
+	// uncompressed (e.g. for `tail -f`). A value of 0 compresses every
+	// rotation in the same cycle that rotated it.
+	CompressAfter int
+	// MaxTotalSize is a size-based retention policy: once the combined
+	// size of rotated .gz archives exceeds it, the oldest archives are
+	// removed. Zero disables the policy.
+	MaxTotalSize int64
+
+	mu               sync.Mutex
+	file             *os.File
+	val              log.Logger
+	maxlinesCurLines int
+	maxsizeCurSize   int
+	dailyOpenDate    int
+
+	compressWg sync.WaitGroup
+	// compressMu serializes compressRotations runs against each other.
+	// Each rotation spawns its own compressRotations goroutine; without
+	// this, two rotations close enough together can both list the same
+	// not-yet-compressed file and call compressFile on it concurrently,
+	// corrupting the resulting .gz (compressWg only lets Close() wait for
+	// them, it doesn't keep them from overlapping).
+	compressMu sync.Mutex
+}
+
+func NewFileWriter() *FileWriter {
+	return &FileWriter{
+		Rotate:   true,
+		Maxlines: 1000000,
+		Maxsize:  1 << 28,
+		Daily:    true,
+		Maxdays:  7,
+	}
+}
+
+// Init opens the log file and resumes any `.gz.tmp` compressions left behind
+// by a previous process that crashed or was killed mid-compression.
+func (wr *FileWriter) Init() error {
+	if err := wr.resumePendingCompressions(); err != nil {
+		Error("Failed to resume pending log compression", "err", err)
+	}
+	return wr.startFile()
+}
+
+func (wr *FileWriter) Log(keyvals ...interface{}) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if err := wr.val.Log(keyvals...); err != nil {
+		return err
+	}
+
+	wr.maxlinesCurLines++
+	if info, err := wr.file.Stat(); err == nil {
+		wr.maxsizeCurSize = int(info.Size())
+	}
+
+	if wr.needsRotate() {
+		if err := wr.doRotate(); err != nil {
+			Error("Failed to rotate log file", "filename", wr.Filename, "err", err)
+		}
+	}
+
+	return nil
+}
+
+func (wr *FileWriter) Reload() error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.file != nil {
+		if err := wr.file.Close(); err != nil {
+			return err
+		}
+	}
+
+	return wr.startFile()
+}
+
+func (wr *FileWriter) Close() error {
+	wr.compressWg.Wait()
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.file == nil {
+		return nil
+	}
+	return wr.file.Close()
+}
+
+func (wr *FileWriter) startFile() error {
+	f, err := os.OpenFile(wr.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errutil.Wrapf(err, "failed to open log file %q", wr.Filename)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	wr.file = f
+	wr.val = wr.Format(f)
+	wr.maxsizeCurSize = int(info.Size())
+	wr.maxlinesCurLines = 0
+	wr.dailyOpenDate = info.ModTime().YearDay()
+	return nil
+}
+
+func (wr *FileWriter) needsRotate() bool {
+	if !wr.Rotate {
+		return false
+	}
+	if wr.Maxlines > 0 && wr.maxlinesCurLines >= wr.Maxlines {
+		return true
+	}
+	if wr.Maxsize > 0 && wr.maxsizeCurSize >= wr.Maxsize {
+		return true
+	}
+	if wr.Daily && time.Now().YearDay() != wr.dailyOpenDate {
+		return true
+	}
+	return false
+}
+
+// doRotate closes the current file, renames it to the next available
+// `<filename>.N` slot, reopens `filename` and kicks off compression/retention
+// in the background. Must be called with wr.mu held.
+func (wr *FileWriter) doRotate() error {
+	if err := wr.file.Close(); err != nil {
+		return err
+	}
+
+	num := wr.nextRotationNum()
+	rotated := fmt.Sprintf("%s.%d", wr.Filename, num)
+	if err := os.Rename(wr.Filename, rotated); err != nil {
+		return err
+	}
+
+	if err := wr.startFile(); err != nil {
+		return err
+	}
+
+	wr.compressWg.Add(1)
+	go wr.compressRotations()
+	return nil
+}
+
+// nextRotationNum returns the next `<filename>.N` suffix: one past the
+// highest N already on disk (1 when there is none), so N keeps increasing
+// even after retention has removed the lowest-numbered rotations. A slot is
+// taken if either the plain `<filename>.N` or its compressed
+// `<filename>.N.gz` sibling exists — compressFile removes the plain file
+// once it's done, so checking only the plain path would let a later
+// rotation reuse a number that's still occupied by an old .gz archive.
+func (wr *FileWriter) nextRotationNum() int {
+	n := 1
+	if rotations, err := wr.listRotations(); err == nil && len(rotations) > 0 {
+		n = rotations[0].num + 1
+	}
+	for {
+		plain := fmt.Sprintf("%s.%d", wr.Filename, n)
+		_, plainErr := os.Stat(plain)
+		_, gzErr := os.Stat(plain + ".gz")
+		if os.IsNotExist(plainErr) && os.IsNotExist(gzErr) {
+			return n
+		}
+		n++
+	}
+}
+
+// compressRotations runs every retention/compression policy for a single
+// rotation cycle: it gzips plain-text rotations once they are at least
+// CompressAfter cycles old (if Compress is set), then applies the
+// size-based (MaxTotalSize) and day-based (Maxdays) retention policies.
+// Runs unconditionally on every rotation, even with Compress disabled, so
+// Maxdays-based pruning still happens for deployments that never opted
+// into compression. Safe to run concurrently with Log(), since it never
+// touches wr.file. Serialized against other compressRotations runs via
+// compressMu, since doRotate spawns one of these per rotation and two
+// overlapping runs could otherwise both compress the same file at once.
+func (wr *FileWriter) compressRotations() {
+	defer wr.compressWg.Done()
+
+	wr.compressMu.Lock()
+	defer wr.compressMu.Unlock()
+
+	if wr.Compress {
+		rotations, err := wr.listRotations()
+		if err != nil {
+			Error("Failed to list rotated log files", "filename", wr.Filename, "err", err)
+			return
+		}
+
+		// rotations is sorted newest (highest N) first; only compress the
+		// ones past the CompressAfter horizon so the freshest rotation(s)
+		// stay plain text.
+		for i, rot := range rotations {
+			if rot.compressed || i < wr.CompressAfter {
+				continue
+			}
+			if err := compressFile(rot.path); err != nil {
+				Error("Failed to compress rotated log file", "filename", rot.path, "err", err)
+			}
+		}
+	}
+
+	if wr.MaxTotalSize > 0 {
+		if err := wr.enforceRetention(); err != nil {
+			Error("Failed to enforce log retention policy", "filename", wr.Filename, "err", err)
+		}
+	}
+
+	if wr.Maxdays > 0 {
+		if err := wr.enforceAgeRetention(); err != nil {
+			Error("Failed to enforce log age retention policy", "filename", wr.Filename, "err", err)
+		}
+	}
+}
+
+type rotationFile struct {
+	path       string
+	num        int
+	size       int64
+	compressed bool
+	modTime    time.Time
+}
+
+// listRotations returns `<filename>.N[.gz]` files sorted newest (highest N)
+// first — N increases with each rotation, so the highest N is the most
+// recently rotated file.
+func (wr *FileWriter) listRotations() ([]rotationFile, error) {
+	dir := filepath.Dir(wr.Filename)
+	base := filepath.Base(wr.Filename)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var rotations []rotationFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base+".") || strings.HasSuffix(name, compressTmpSuffix) {
+			continue
+		}
+		suffix := strings.TrimPrefix(name, base+".")
+		compressed := strings.HasSuffix(suffix, ".gz")
+		suffix = strings.TrimSuffix(suffix, ".gz")
+
+		num, err := strconv.Atoi(suffix)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		rotations = append(rotations, rotationFile{
+			path:       filepath.Join(dir, name),
+			num:        num,
+			size:       info.Size(),
+			compressed: compressed,
+			modTime:    info.ModTime(),
+		})
+	}
+
+	sort.Slice(rotations, func(i, j int) bool { return rotations[i].num > rotations[j].num })
+	return rotations, nil
+}
+
+// enforceRetention deletes the oldest .gz archives once their combined size
+// exceeds MaxTotalSize.
+func (wr *FileWriter) enforceRetention() error {
+	rotations, err := wr.listRotations()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, rot := range rotations {
+		if !rot.compressed {
+			continue
+		}
+		total += rot.size
+	}
+
+	// rotations is sorted newest-first (highest N first), so the oldest
+	// archives are at the back; walk backwards, deleting until we're back
+	// under budget.
+	for i := len(rotations) - 1; i >= 0; i-- {
+		if total <= wr.MaxTotalSize {
+			break
+		}
+		rot := rotations[i]
+		if !rot.compressed {
+			continue
+		}
+		if err := os.Remove(rot.path); err != nil {
+			return err
+		}
+		total -= rot.size
+	}
+
+	return nil
+}
+
+// enforceAgeRetention deletes rotations (compressed or not) whose mtime is
+// older than Maxdays, the day-based counterpart to enforceRetention's
+// size-based policy. A rotation's mtime reflects when doRotate renamed it
+// off the live filename, so it's effectively the rotation's age.
+func (wr *FileWriter) enforceAgeRetention() error {
+	rotations, err := wr.listRotations()
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -int(wr.Maxdays))
+	for _, rot := range rotations {
+		if rot.modTime.Before(cutoff) {
+			if err := os.Remove(rot.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// compressFile gzips src into `src.gz`, writing to a `.gz.tmp` sibling first
+// and atomically renaming it into place so a crash mid-compression never
+// leaves a truncated `.gz` behind.
+func compressFile(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	tmp := src + ".gz" + compressTmpSuffix
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = gz.Close()
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, src+".gz"); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+// resumePendingCompressions finishes any `.gz.tmp` files left behind by a
+// process that crashed or was killed mid-compression, so a restart never
+// loses the source file nor leaves a half-written archive around.
+func (wr *FileWriter) resumePendingCompressions() error {
+	dir := filepath.Dir(wr.Filename)
+	base := filepath.Base(wr.Filename)
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base+".") || !strings.HasSuffix(name, compressTmpSuffix) {
+			continue
+		}
+
+		tmpPath := filepath.Join(dir, name)
+		src := strings.TrimSuffix(strings.TrimSuffix(tmpPath, compressTmpSuffix), ".gz")
+		finalGz := src + ".gz"
+
+		if _, err := os.Stat(src); err == nil {
+			// The source is still there, so the previous run never got to
+			// rename; just redo the compression from scratch.
+			_ = os.Remove(tmpPath)
+			if err := compressFile(src); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The source is already gone, so the tmp file can't be rebuilt: it
+		// is promoted only if it decompresses cleanly, and a partial
+		// leftover is removed, the same as compressFile does with its tmp
+		// file on failure.
+		f, err := os.Open(tmpPath)
+		if err != nil {
+			return err
+		}
+		gz, err := gzip.NewReader(f)
+		if err == nil {
+			_, err = io.Copy(io.Discard, gz)
+		}
+		_ = f.Close()
+		if err != nil {
+			_ = os.Remove(tmpPath)
+			continue
+		}
+		if err := os.Rename(tmpPath, finalGz); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -42,30 +43,17 @@ func (ml *MultiLoggers) LogWithLevel(fn func(log.Logger) log.Logger, keyvals ...
 	}
 }
 
-var Root MultiLoggers
-var loggersToClose []DisposableHandler
-var loggersToReload []ReloadableHandler
-
-// var filters map[string]level.Option
-
-func init() {
-	loggersToClose = make([]DisposableHandler, 0)
-	loggersToReload = make([]ReloadableHandler, 0)
-
-	// // Initialize the logger with output os.stderr
-	// Root = log.NewLogfmtLogger(os.Stderr)
-	// create map from log level string to level.Option
-	// filters = map[string]level.Option{}
-}
-
-func New(logger string, ctx ...interface{}) MultiLoggers {
-	params := append([]interface{}{"logger", logger}, ctx...)
-	var newloger MultiLoggers
-	for _, val := range Root.loggers {
-		val.val = log.With(val.val, params...)
-		newloger.loggers = append(newloger.loggers, val)
-	}
-	return newloger
+// Root, loggersToClose and loggersToReload used to be the package's only
+// state. They now live on defaultManager (see service.go); New/Close/Reload
+// below are thin shims over it kept for the many callers that still import
+// the log package directly instead of taking a log.Service.
+
+// New returns a logger scoped with `logger=logger` plus any extra ctx
+// keyvals, fanned out across every handler the default Manager was
+// configured with. Prefer accepting a log.Service via constructor injection
+// in new code; this is kept for backward compatibility.
+func New(logger string, ctx ...interface{}) Logger {
+	return defaultManager.New(logger, ctx...)
 }
 
 func Tracef(format string, v ...interface{}) {
@@ -75,7 +63,7 @@ func Tracef(format string, v ...interface{}) {
 	} else {
 		message = format
 	}
-	Root.LogWithLevel(level.Debug, "msg", message)
+	defaultManager.root.LogWithLevel(level.Debug, "msg", message)
 }
 
 func Debugf(format string, v ...interface{}) {
@@ -85,7 +73,7 @@ func Debugf(format string, v ...interface{}) {
 	} else {
 		message = format
 	}
-	Root.LogWithLevel(level.Debug, "msg", message)
+	defaultManager.root.LogWithLevel(level.Debug, "msg", message)
 }
 
 func Infof(format string, v ...interface{}) {
@@ -95,12 +83,12 @@ func Infof(format string, v ...interface{}) {
 	} else {
 		message = format
 	}
-	Root.LogWithLevel(level.Info, "msg", message)
+	defaultManager.root.LogWithLevel(level.Info, "msg", message)
 }
 
 func Warn(msg string, v ...interface{}) {
 	params := append([]interface{}{"msg", msg}, v...)
-	Root.LogWithLevel(level.Warn, "msg", params)
+	defaultManager.root.LogWithLevel(level.Warn, "msg", params)
 }
 
 func Warnf(format string, v ...interface{}) {
@@ -110,22 +98,22 @@ func Warnf(format string, v ...interface{}) {
 	} else {
 		message = format
 	}
-	Root.LogWithLevel(level.Warn, "msg", message)
+	defaultManager.root.LogWithLevel(level.Warn, "msg", message)
 }
 
 func Error(msg string, args ...interface{}) {
 	params := append([]interface{}{"msg", msg}, args...)
-	Root.LogWithLevel(level.Error, params...)
+	defaultManager.root.LogWithLevel(level.Error, params...)
 }
 
 // TODO: need to check what is this skip that never used? :D
 func Errorf(skip int, format string, v ...interface{}) {
-	Root.LogWithLevel(level.Error, "msg", fmt.Sprintf(format, v...))
+	defaultManager.root.LogWithLevel(level.Error, "msg", fmt.Sprintf(format, v...))
 }
 
 // TODO: in the go-kit/log we don't have log level critical, use error instead
 func Fatalf(skip int, format string, v ...interface{}) {
-	Root.LogWithLevel(level.Error, "msg", fmt.Sprintf(format, v...))
+	defaultManager.root.LogWithLevel(level.Error, "msg", fmt.Sprintf(format, v...))
 	if err := Close(); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to close log: %s\n", err)
 	}
@@ -216,6 +204,10 @@ func getLoggerOfFormat(format string) Formatedlogger {
 			// return log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout))
 			return log.NewLogfmtLogger(w)
 		}
+	case "ncsa":
+		return func(w io.Writer) log.Logger {
+			return ncsaLogger{w: w}
+		}
 	case "text":
 		fallthrough
 	default:
@@ -227,40 +219,47 @@ func getLoggerOfFormat(format string) Formatedlogger {
 }
 
 // --------------------------------------------------------------------------------------
-func Close() error {
-	var err error
-	for _, logger := range loggersToClose {
-		if e := logger.Close(); e != nil && err == nil {
-			err = e
-		}
-	}
-	loggersToClose = make([]DisposableHandler, 0)
 
-	return err
+// Close releases every handler owned by the default Manager. Prefer
+// Manager.Close on an explicit Service in new code.
+func Close() error {
+	return defaultManager.Close()
 }
 
-// Reload all loggers.
+// Reload re-reads every reloadable handler owned by the default Manager.
+// Prefer Manager.Reload on an explicit Service in new code.
 func Reload() error {
-	for _, logger := range loggersToReload {
-		if err := logger.Reload(); err != nil {
-			return err
-		}
-	}
-	return nil
+	return defaultManager.Reload()
 }
 
+// ReadLoggingConfig (re)configures the default Manager from cfg. Prefer
+// constructing a Manager explicitly (see NewManager) and passing it through
+// as a log.Service in new code.
 func ReadLoggingConfig(modes []string, logsPath string, cfg *ini.File) error {
-	if err := Close(); err != nil {
+	return defaultManager.Configure(modes, logsPath, cfg)
+}
+
+// configureManager does the actual work behind Manager.Configure.
+func configureManager(m *Manager, modes []string, logsPath string, cfg *ini.File) error {
+	if err := m.Close(); err != nil {
 		return err
 	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// the default log level
 	defaultLevelName, _ := getLogLevelFromConfig("log", "info", cfg)
 
 	// the log level filter per logger
 	defaultFilters := getFilters(util.SplitString(cfg.Section("log").Key("filters").String()))
 
+	configureNcsaTimeZone(cfg)
+
 	// Initialize the root multi logger with settings
-	Root = MultiLoggers{}
+	m.root = MultiLoggers{}
+
+	var handlers []log.Logger
 
 	// get all the supported modes, and the configuration of the selected mode
 	for _, mode := range modes {
@@ -277,43 +276,9 @@ func ReadLoggingConfig(modes []string, logsPath string, cfg *ini.File) error {
 		// get log filter for the dedicated mode, we need to store the map, since now the "sub logger" is not created yet
 		modeFilters := getFilters(util.SplitString(sec.Key("filters").String()))
 
-		handlerfn := getLoggerOfFormat(sec.Key("format").MustString(""))
-		var handler log.Logger
-
-		switch mode {
-		case "console":
-			handler = handlerfn(os.Stdout)
-		case "file":
-			fileName := sec.Key("file_name").MustString(filepath.Join(logsPath, "grafana.log"))
-			dpath := filepath.Dir(fileName)
-			if err := os.MkdirAll(dpath, os.ModePerm); err != nil {
-				log.Error("Failed to create directory", "dpath", dpath, "err", err)
-				return errutil.Wrapf(err, "failed to create log directory %q", dpath)
-			}
-			fileHandler := NewFileWriter()
-			fileHandler.Filename = fileName
-			fileHandler.Format = formattedLogger
-			fileHandler.Rotate = sec.Key("log_rotate").MustBool(true)
-			fileHandler.Maxlines = sec.Key("max_lines").MustInt(1000000)
-			fileHandler.Maxsize = 1 << uint(sec.Key("max_size_shift").MustInt(28))
-			fileHandler.Daily = sec.Key("daily_rotate").MustBool(true)
-			fileHandler.Maxdays = sec.Key("max_days").MustInt64(7)
-			if err := fileHandler.Init(); err != nil {
-				Root.Error("Failed to initialize file handler", "dpath", dpath, "err", err)
-				return errutil.Wrapf(err, "failed to initialize file handler")
-			}
-
-			loggersToClose = append(loggersToClose, fileHandler)
-			loggersToReload = append(loggersToReload, fileHandler)
-			handler = fileHandler
-		case "syslog":
-			sysLogHandler := NewSyslog(sec, format)
-
-			loggersToClose = append(loggersToClose, sysLogHandler)
-			handler = sysLogHandler
-		}
-		if handler == nil {
-			panic(fmt.Sprintf("Handler is uninitialized for mode %q", mode))
+		handler, err := newModeHandler(m, mode, sec, logsPath)
+		if err != nil {
+			return err
 		}
 
 		// we always add the default filter as supplementary if not overwrite in the mode filter
@@ -330,11 +295,133 @@ func ReadLoggingConfig(modes []string, logsPath string, cfg *ini.File) error {
 		// 	}
 		// }
 
+		bufferLen := sec.Key("buffer_len").MustInt(10000)
+		overflow := OverflowStrategy(sec.Key("overflow").MustString(string(OverflowBlock)))
+		closeTimeout := sec.Key("buffer_close_timeout").MustDuration(5 * time.Second)
+		bufferedHandler := NewBufferedHandler(mode, handler, bufferLen, overflow, closeTimeout)
+		m.loggersToClose = append(m.loggersToClose, bufferedHandler)
+		handler = bufferedHandler
+
 		handler = LogFilterHandler(level, modeFilters, handler)
 		handlers = append(handlers, handler)
 	}
 
-	Root.SetHandler(log.MultiHandler(handlers...))
+	for _, h := range handlers {
+		m.root.loggers = append(m.root.loggers, LogWithFilters{val: h})
+	}
+	return nil
+}
+
+// newModeHandler builds the underlying handler for a single `[log.<mode>]`
+// (or equivalent) section: console, file, router or syslog. It is shared by
+// configureManager, ReadAuditConfig and ReadRouterConfig so each dedicated
+// sink can reuse the same mode wiring instead of duplicating it. Any
+// DisposableHandler/ReloadableHandler it creates is registered against m.
+func newModeHandler(m *Manager, mode string, sec *ini.Section, logsPath string) (log.Logger, error) {
+	handlerfn := getLoggerOfFormat(sec.Key("format").MustString(""))
+	var handler log.Logger
+
+	switch mode {
+	case "console":
+		handler = handlerfn(os.Stdout)
+	case "file", "router":
+		defaultFileName := "grafana.log"
+		if mode == "router" {
+			defaultFileName = "grafana_router.log"
+		}
+		fileName := sec.Key("file_name").MustString(filepath.Join(logsPath, defaultFileName))
+		dpath := filepath.Dir(fileName)
+		if err := os.MkdirAll(dpath, os.ModePerm); err != nil {
+			log.Error("Failed to create directory", "dpath", dpath, "err", err)
+			return nil, errutil.Wrapf(err, "failed to create log directory %q", dpath)
+		}
+		fileHandler := NewFileWriter()
+		fileHandler.Filename = fileName
+		fileHandler.Format = handlerfn
+		fileHandler.Rotate = sec.Key("log_rotate").MustBool(true)
+		fileHandler.Maxlines = sec.Key("max_lines").MustInt(1000000)
+		fileHandler.Maxsize = 1 << uint(sec.Key("max_size_shift").MustInt(28))
+		fileHandler.Daily = sec.Key("daily_rotate").MustBool(true)
+		fileHandler.Maxdays = sec.Key("max_days").MustInt64(7)
+		fileHandler.Compress = sec.Key("compress").MustBool(false)
+		fileHandler.CompressAfter = sec.Key("compress_after").MustInt(0)
+		fileHandler.MaxTotalSize = sec.Key("max_total_size").MustInt64(0)
+		if err := fileHandler.Init(); err != nil {
+			Error("Failed to initialize file handler", "dpath", dpath, "err", err)
+			return nil, errutil.Wrapf(err, "failed to initialize file handler")
+		}
+
+		m.loggersToClose = append(m.loggersToClose, fileHandler)
+		m.loggersToReload = append(m.loggersToReload, fileHandler)
+		handler = fileHandler
+	case "syslog":
+		sysLogHandler := NewSyslog(sec, format)
+
+		m.loggersToClose = append(m.loggersToClose, sysLogHandler)
+		handler = sysLogHandler
+	}
+	if handler == nil {
+		panic(fmt.Sprintf("Handler is uninitialized for mode %q", mode))
+	}
+	return handler, nil
+}
+
+// auditLogger is the dedicated sink configured by ReadAuditConfig. It is
+// write-only from the perspective of the rest of the codebase: callers use
+// log.Audit, never log.New("audit").
+var auditLogger MultiLoggers
+
+// ReadAuditConfig configures the dedicated sink for tamper-evident audit
+// records (see Audit). It reuses the same mode wiring as ReadLoggingConfig,
+// pointed at the `[audit]` section instead of `[log.<mode>]`, and always
+// runs the handler behind a blocking BufferedHandler so an audit record is
+// never silently dropped under load.
+func ReadAuditConfig(cfg *ini.File, logsPath string) error {
+	sec := cfg.Section("audit")
+	mode := strings.TrimSpace(sec.Key("mode").MustString("file"))
+
+	handler, err := newModeHandler(defaultManager, mode, sec, logsPath)
+	if err != nil {
+		return errutil.Wrapf(err, "failed to configure audit sink")
+	}
+
+	bufferedHandler := NewBufferedHandler("audit", handler, sec.Key("buffer_len").MustInt(10000), OverflowBlock, sec.Key("buffer_close_timeout").MustDuration(5*time.Second))
+	defaultManager.mu.Lock()
+	defaultManager.loggersToClose = append(defaultManager.loggersToClose, bufferedHandler)
+	defaultManager.mu.Unlock()
+
+	auditLogger = MultiLoggers{loggers: []LogWithFilters{{val: log.With(bufferedHandler, "logger", "audit")}}}
+	return nil
+}
+
+// routerLogger is the dedicated sink configured by ReadRouterConfig. Like
+// auditLogger, it is a separate pipeline from the fanned-out root logger so
+// HTTP access logs land in their own sink (e.g. a dedicated file) instead of
+// being interleaved with application logs. Callers use NewRouterLogger,
+// never log.New("router").
+var routerLogger MultiLoggers
+
+// ReadRouterConfig configures the dedicated sink for HTTP router access logs
+// (see NewRouterLogger). It reuses the same mode wiring as ReadLoggingConfig,
+// pointed at the `[log.router]` section with mode "router" (newModeHandler
+// defaults its file sink to grafana_router.log so it doesn't collide with
+// the application log by default). Unlike audit records, a dropped access
+// log line isn't a correctness problem, so the buffer drops the oldest queued
+// line rather than blocking request handling when the sink falls behind.
+func ReadRouterConfig(cfg *ini.File, logsPath string) error {
+	sec := cfg.Section("log.router")
+
+	handler, err := newModeHandler(defaultManager, "router", sec, logsPath)
+	if err != nil {
+		return errutil.Wrapf(err, "failed to configure router sink")
+	}
+
+	bufferedHandler := NewBufferedHandler("router", handler, sec.Key("buffer_len").MustInt(10000), OverflowDropOldest, sec.Key("buffer_close_timeout").MustDuration(5*time.Second))
+	defaultManager.mu.Lock()
+	defaultManager.loggersToClose = append(defaultManager.loggersToClose, bufferedHandler)
+	defaultManager.mu.Unlock()
+
+	routerLogger = MultiLoggers{loggers: []LogWithFilters{{val: log.With(bufferedHandler, "logger", "router")}}}
 	return nil
 }
 
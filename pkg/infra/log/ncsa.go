@@ -0,0 +1,119 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// clfTimeFormat is the timestamp format used by the Apache Common/Combined
+// Log Format, e.g. "29/Jul/2026:10:15:04 +0000".
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// ncsaLocation is the timezone ncsaLogger stamps %t with. Configured from
+// `[log] time_zone` by configureNcsaTimeZone; defaults to the server's
+// local timezone.
+var ncsaLocation = time.Local
+
+// configureNcsaTimeZone sets ncsaLocation from `[log] time_zone` (an IANA
+// zone name, or "Local"/"UTC"). Called once per configureManager run, same
+// as every other `[log]`-wide setting.
+func configureNcsaTimeZone(cfg *ini.File) {
+	name := cfg.Section("log").Key("time_zone").MustString("Local")
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		Error("Unknown log time_zone, falling back to Local", "time_zone", name, "err", err)
+		loc = time.Local
+	}
+	ncsaLocation = loc
+}
+
+// NewRouterLogger returns the dedicated routerLogger pipeline configured by
+// ReadRouterConfig, ready for the HTTP router middleware to call with the
+// keyvals documented on ncsaLogger. Unlike log.New, it doesn't fan out
+// across the application's handlers; it writes only to the `[log.router]`
+// sink, separate from application logs.
+//
+// This package has no HTTP router/middleware of its own: wiring an actual
+// per-request call to NewRouterLogger().Log(...) belongs in the
+// application's HTTP middleware package, alongside the ReadRouterConfig
+// call at startup. Until both are added there, this is a dead-letter
+// pipeline — MultiLoggers.Log on a zero-value routerLogger is a safe no-op,
+// so that's silent rather than a crash, but it does mean no access log
+// lines are produced.
+func NewRouterLogger() MultiLoggers {
+	return routerLogger
+}
+
+// ncsaLogger formats records as the Apache Combined Log Format:
+//
+//	%h %l %u %t "%r" %>s %b "%{Referer}" "%{User-Agent}"
+//
+// It expects the keyvals written by the router middleware: remote, user,
+// method, path, proto, status, size, referer, ua. duration_ms is accepted
+// too (the router middleware always sends it) but isn't part of the
+// Combined Log Format, so it's not written to the line — keeping output
+// parseable by standard CLF tooling.
+type ncsaLogger struct {
+	w io.Writer
+}
+
+func (n ncsaLogger) Log(keyvals ...interface{}) error {
+	fields := keyvalsToMap(keyvals)
+
+	remote := fieldOr(fields, "remote", "-")
+	user := fieldOr(fields, "user", "-")
+	method := fieldOr(fields, "method", "-")
+	path := fieldOr(fields, "path", "-")
+	proto := fieldOr(fields, "proto", "-")
+	status := fieldOr(fields, "status", "-")
+	size := fieldOr(fields, "size", "-")
+	referer := fieldOr(fields, "referer", "-")
+	ua := fieldOr(fields, "ua", "-")
+
+	line := fmt.Sprintf(
+		"%s - %s [%s] \"%s %s %s\" %s %s %q %q\n",
+		remote, user, time.Now().In(ncsaLocation).Format(clfTimeFormat),
+		method, path, proto,
+		status, size,
+		quoteField(referer), quoteField(ua),
+	)
+
+	_, err := io.WriteString(n.w, line)
+	return err
+}
+
+func keyvalsToMap(keyvals []interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			continue
+		}
+		fields[key] = keyvals[i+1]
+	}
+	return fields
+}
+
+func fieldOr(fields map[string]interface{}, key string, fallback string) string {
+	v, ok := fields[key]
+	if !ok || v == nil {
+		return fallback
+	}
+	s := fmt.Sprintf("%v", v)
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// quoteField replaces characters that would break the quoted CLF field
+// (double quotes with ', backslashes with /, and CR/LF with a space) rather
+// than emitting them unescaped into the log line.
+func quoteField(s string) string {
+	replacer := strings.NewReplacer(`"`, `'`, `\`, `/`, "\n", " ", "\r", " ")
+	return replacer.Replace(s)
+}
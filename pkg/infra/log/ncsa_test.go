@@ -0,0 +1,88 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+func TestNcsaLoggerQuotesUnsafeFields(t *testing.T) {
+	var buf bytes.Buffer
+	n := ncsaLogger{w: &buf}
+
+	err := n.Log(
+		"remote", "127.0.0.1",
+		"user", "-",
+		"method", "GET",
+		"path", "/x",
+		"proto", "HTTP/1.1",
+		"status", "200",
+		"size", "12",
+		"referer", `evil"ref\erer`,
+		"ua", "agent\nwith\rnewlines",
+	)
+	if err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+
+	line := buf.String()
+	if strings.Contains(line, "\n\"") || strings.Count(line, "\n") != 1 {
+		t.Fatalf("unescaped newline broke the line framing: %q", line)
+	}
+	if strings.Contains(line, `evil"ref`) {
+		t.Fatalf("double quote from referer was not escaped: %q", line)
+	}
+	if strings.Contains(line, `\erer`) {
+		t.Fatalf("backslash from referer was not escaped: %q", line)
+	}
+	if !strings.Contains(line, "agent with newlines") {
+		t.Fatalf("newline/carriage-return in user-agent was not replaced with a space: %q", line)
+	}
+}
+
+func TestNcsaLoggerMissingFieldsFallBackToDash(t *testing.T) {
+	var buf bytes.Buffer
+	n := ncsaLogger{w: &buf}
+
+	if err := n.Log("remote", "127.0.0.1"); err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+
+	line := buf.String()
+	if !strings.Contains(line, `"- - -"`) {
+		t.Fatalf("expected missing method/path/proto to render as dashes, got: %q", line)
+	}
+}
+
+func TestConfigureNcsaTimeZoneFallsBackOnUnknownZone(t *testing.T) {
+	defer func() { ncsaLocation = time.Local }()
+
+	cfg := ini.Empty()
+	if _, err := cfg.Section("log").NewKey("time_zone", "Not/A_Real_Zone"); err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+
+	configureNcsaTimeZone(cfg)
+
+	if ncsaLocation != time.Local {
+		t.Fatalf("expected fallback to time.Local for an unknown zone, got %v", ncsaLocation)
+	}
+}
+
+func TestConfigureNcsaTimeZoneLoadsConfiguredZone(t *testing.T) {
+	defer func() { ncsaLocation = time.Local }()
+
+	cfg := ini.Empty()
+	if _, err := cfg.Section("log").NewKey("time_zone", "UTC"); err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+
+	configureNcsaTimeZone(cfg)
+
+	if ncsaLocation != time.UTC {
+		t.Fatalf("expected ncsaLocation to be UTC, got %v", ncsaLocation)
+	}
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log/level"
+	xormlog "xorm.io/xorm/log"
+)
+
+// XormLogger adapts a MultiLoggers to xorm's log.Logger interface so that
+// every SQL query, transaction and error xorm emits flows through the same
+// MultiLoggers pipeline as the rest of Grafana (logger=sqlstore), instead of
+// going to its own ad hoc logger.
+type XormLogger struct {
+	logger MultiLoggers
+
+	level   xormlog.LogLevel
+	showSQL bool
+
+	// slowQueryLogger is a dedicated "sqlstore.slowquery" logger so slow
+	// queries can be filtered independently of the general sqlstore logger
+	// (e.g. subscribed to via `filters = sqlstore.slowquery:warn` even when
+	// `sqlstore` itself is set to `error`).
+	slowQueryLogger MultiLoggers
+	slowThreshold   time.Duration
+
+	// slowOnly is `[log] slow_only`. When true AfterSQL no longer logs
+	// successful queries through the general sqlstore logger (failed queries
+	// are still logged there), so operators can subscribe to
+	// sqlstore.slowquery alone without also wiring a level filter for the
+	// noisy per-query logs. The Debug/Info/Warn/Error methods are not
+	// affected by it.
+	slowOnly bool
+}
+
+// NewXormLogger builds an XormLogger scoped to logger=sqlstore, using svc
+// instead of the package-level default Manager so callers can inject a
+// Service built for tests or a specific engine. Queries slower than
+// slowThreshold are additionally logged at warn level through
+// "sqlstore.slowquery". When slowOnly is true, AfterSQL logs only failed
+// queries through the general sqlstore logger; the slow-query warn log still
+// fires.
+func NewXormLogger(svc Service, slowThreshold time.Duration, slowOnly bool) *XormLogger {
+	return &XormLogger{
+		logger:          svc.New("sqlstore"),
+		level:           xormlog.LOG_INFO,
+		slowQueryLogger: svc.New("sqlstore.slowquery"),
+		slowThreshold:   slowThreshold,
+		slowOnly:        slowOnly,
+	}
+}
+
+func (s *XormLogger) Debug(v ...interface{}) { s.logger.LogWithLevel(level.Debug, "msg", fmt.Sprint(v...)) }
+func (s *XormLogger) Debugf(format string, v ...interface{}) {
+	s.logger.LogWithLevel(level.Debug, "msg", fmt.Sprintf(format, v...))
+}
+
+func (s *XormLogger) Error(v ...interface{}) { s.logger.LogWithLevel(level.Error, "msg", fmt.Sprint(v...)) }
+func (s *XormLogger) Errorf(format string, v ...interface{}) {
+	s.logger.LogWithLevel(level.Error, "msg", fmt.Sprintf(format, v...))
+}
+
+func (s *XormLogger) Info(v ...interface{}) { s.logger.LogWithLevel(level.Info, "msg", fmt.Sprint(v...)) }
+func (s *XormLogger) Infof(format string, v ...interface{}) {
+	s.logger.LogWithLevel(level.Info, "msg", fmt.Sprintf(format, v...))
+}
+
+func (s *XormLogger) Warn(v ...interface{}) { s.logger.LogWithLevel(level.Warn, "msg", fmt.Sprint(v...)) }
+func (s *XormLogger) Warnf(format string, v ...interface{}) {
+	s.logger.LogWithLevel(level.Warn, "msg", fmt.Sprintf(format, v...))
+}
+
+func (s *XormLogger) Level() xormlog.LogLevel     { return s.level }
+func (s *XormLogger) SetLevel(l xormlog.LogLevel) { s.level = l }
+
+func (s *XormLogger) ShowSQL(show ...bool) {
+	if len(show) == 0 {
+		s.showSQL = true
+		return
+	}
+	s.showSQL = show[0]
+}
+
+func (s *XormLogger) IsShowSQL() bool { return s.showSQL }
+
+// BeforeSQL is part of the xorm.io/xorm/log.Logger interface; we only need
+// AfterSQL to measure execution time.
+func (s *XormLogger) BeforeSQL(_ xormlog.LogContext) {}
+
+func (s *XormLogger) AfterSQL(ctx xormlog.LogContext) {
+	if ctx.Err != nil {
+		s.logger.LogWithLevel(level.Error, "msg", "SQL execution failed", "sql", ctx.SQL, "args_count", len(ctx.Args), "duration_ms", ctx.ExecuteTime.Milliseconds(), "err", ctx.Err)
+		return
+	}
+
+	if s.showSQL && !s.slowOnly {
+		s.logger.LogWithLevel(level.Debug, "msg", "SQL executed", "sql", ctx.SQL, "args_count", len(ctx.Args), "duration_ms", ctx.ExecuteTime.Milliseconds())
+	}
+
+	if s.slowThreshold > 0 && ctx.ExecuteTime > s.slowThreshold {
+		s.slowQueryLogger.LogWithLevel(level.Warn, "msg", "Slow query", "duration_ms", ctx.ExecuteTime.Milliseconds(), "sql", ctx.SQL, "args_count", len(ctx.Args), "sql_hash", sqlHash(ctx.SQL))
+	}
+}
+
+// sqlHash returns a short, stable fingerprint of a SQL statement so slow
+// query logs can be grouped/searched by one short key; the full statement
+// is still logged alongside it as `sql`.
+func sqlHash(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return fmt.Sprintf("%x", sum)[:12]
+}
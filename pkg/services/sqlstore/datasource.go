@@ -8,6 +8,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/events"
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/metrics"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/util/errutil"
@@ -95,9 +96,21 @@ func (ss *SQLStore) DeleteDataSource(ctx context.Context, cmd *models.DeleteData
 		return models.ErrDataSourceIdentifierNotSet
 	}
 
-	return inTransactionCtx(ctx, func(sess *DBSession) error {
+	var existing models.DataSource
+	var hasExisting bool
+
+	err := inTransactionCtx(ctx, func(sess *DBSession) error {
+		// Read the datasource before it's gone so the audit record has
+		// ds_type and version_before, not just the identifiers the caller
+		// passed in.
+		existing = models.DataSource{Id: cmd.ID, Uid: cmd.UID, Name: cmd.Name, OrgId: cmd.OrgID}
+		hasExisting, _ = sess.Get(&existing)
+
 		result, err := sess.Exec(params...)
 		cmd.DeletedDatasourcesCount, _ = result.RowsAffected()
+		if err != nil {
+			return err
+		}
 
 		sess.publishAfterCommit(&events.DataSourceDeleted{
 			Timestamp: time.Now(),
@@ -107,12 +120,31 @@ func (ss *SQLStore) DeleteDataSource(ctx context.Context, cmd *models.DeleteData
 			OrgID:     cmd.OrgID,
 		})
 
-		return err
+		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// Emitted after inTransactionCtx has committed, same as the
+	// publishAfterCommit bus events above: a rolled-back transaction must
+	// never produce an audit record for a deletion that didn't happen.
+	if hasExisting && cmd.DeletedDatasourcesCount > 0 {
+		log.Audit(ctx, log.AuditRecord{
+			Action:        "delete",
+			DSUID:         existing.Uid,
+			DSType:        existing.Type,
+			OrgID:         cmd.OrgID,
+			VersionBefore: existing.Version,
+			VersionAfter:  0,
+		})
+	}
+
+	return nil
 }
 
 func (ss *SQLStore) AddDataSource(ctx context.Context, cmd *models.AddDataSourceCommand) error {
-	return inTransactionCtx(ctx, func(sess *DBSession) error {
+	err := inTransactionCtx(ctx, func(sess *DBSession) error {
 		existing := models.DataSource{OrgId: cmd.OrgId, Name: cmd.Name}
 		has, _ := sess.Get(&existing)
 
@@ -174,8 +206,26 @@ func (ss *SQLStore) AddDataSource(ctx context.Context, cmd *models.AddDataSource
 			UID:       cmd.Uid,
 			OrgID:     cmd.OrgId,
 		})
+
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// Emitted after inTransactionCtx has committed, same as the
+	// publishAfterCommit bus event above: a rolled-back transaction must
+	// never produce an audit record for a datasource that was never created.
+	log.Audit(ctx, log.AuditRecord{
+		Action:        "create",
+		DSUID:         cmd.Result.Uid,
+		DSType:        cmd.Result.Type,
+		OrgID:         cmd.Result.OrgId,
+		VersionBefore: 0,
+		VersionAfter:  cmd.Result.Version,
+	})
+
+	return nil
 }
 
 func updateIsDefaultFlag(ds *models.DataSource, sess *DBSession) error {
@@ -190,7 +240,7 @@ func updateIsDefaultFlag(ds *models.DataSource, sess *DBSession) error {
 }
 
 func (ss *SQLStore) UpdateDataSource(ctx context.Context, cmd *models.UpdateDataSourceCommand) error {
-	return inTransactionCtx(ctx, func(sess *DBSession) error {
+	err := inTransactionCtx(ctx, func(sess *DBSession) error {
 		if cmd.JsonData == nil {
 			cmd.JsonData = simplejson.New()
 		}
@@ -248,10 +298,31 @@ func (ss *SQLStore) UpdateDataSource(ctx context.Context, cmd *models.UpdateData
 		}
 
 		err = updateIsDefaultFlag(ds, sess)
+		if err != nil {
+			return err
+		}
 
 		cmd.Result = ds
+
+		return nil
+	})
+	if err != nil {
 		return err
+	}
+
+	// Emitted after inTransactionCtx has committed: an audit record for an
+	// update that was rolled back (e.g. ErrDataSourceUpdatingOldVersion)
+	// would otherwise survive in the chain with nothing behind it.
+	log.Audit(ctx, log.AuditRecord{
+		Action:        "update",
+		DSUID:         cmd.Result.Uid,
+		DSType:        cmd.Result.Type,
+		OrgID:         cmd.Result.OrgId,
+		VersionBefore: cmd.Version,
+		VersionAfter:  cmd.Result.Version,
 	})
+
+	return nil
 }
 
 func generateNewDatasourceUid(sess *DBSession, orgId int64) (string, error) {
@@ -0,0 +1,30 @@
+package sqlstore
+
+import (
+	"github.com/grafana/grafana/pkg/infra/log"
+	"gopkg.in/ini.v1"
+	"xorm.io/xorm"
+)
+
+// x is the shared xorm engine every SQLStore method queries through. It's a
+// package-level var rather than a SQLStore field because the rest of this
+// package already treats it that way: query methods like GetDataSources and
+// GetDefaultDataSource reach x directly instead of going through their
+// *SQLStore receiver.
+var x *xorm.Engine
+
+// SQLStore is the DI-friendly handle to the database layer. Callers get one
+// through NewSQLStore rather than constructing it directly.
+type SQLStore struct{}
+
+// NewSQLStore wires engine as the package's shared xorm engine and returns
+// a SQLStore bound to it. initXormLogger is called here, before any query
+// can run, so every query/transaction/error already goes through logger
+// (logger=sqlstore) from the first call onward.
+func NewSQLStore(engine *xorm.Engine, cfg *ini.File, logger log.Service) *SQLStore {
+	x = engine
+
+	ss := &SQLStore{}
+	ss.initXormLogger(cfg, logger)
+	return ss
+}
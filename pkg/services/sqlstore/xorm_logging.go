@@ -0,0 +1,18 @@
+package sqlstore
+
+import (
+	"github.com/grafana/grafana/pkg/infra/log"
+	"gopkg.in/ini.v1"
+)
+
+// initXormLogger wires xorm's internal logger through logger (accepted via
+// constructor injection rather than the package-level log.New) so every
+// query, transaction commit and error goes through the same pipeline
+// (logger=sqlstore) instead of the ad hoc sqlog.Error calls sprinkled
+// through this package. Called once the engine is created, before any
+// queries are run.
+func (ss *SQLStore) initXormLogger(cfg *ini.File, logger log.Service) {
+	threshold := cfg.Section("log").Key("slow_query_threshold").MustDuration(0)
+	slowOnly := cfg.Section("log").Key("slow_only").MustBool(false)
+	x.SetLogger(log.NewXormLogger(logger, threshold, slowOnly))
+}